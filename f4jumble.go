@@ -20,6 +20,11 @@
 // jumbled bytes. Specifically, F4Jumble uses an unkeyed 4-round Feistel construction to
 // approximate a random permutation.
 //
+// F4Jumble/F4JumbleInv domain-separate their hashing with a personalization string
+// tying them to ZIP-316 unified addresses. Other protocols that want the same
+// cascading-encoding primitive with their own domain separation and message length
+// bounds can use [Config] directly instead of forking this package.
+//
 // [Diagram of 4-round unkeyed Feistel construction](https://zips.z.cash/zip-0316-f4.png)
 //
 // [Base64]: https://en.wikipedia.org/wiki/Base64
@@ -28,6 +33,7 @@ package f4jumble
 
 import (
 	"errors"
+	"sync"
 
 	"github.com/gtank/blake2/blake2b"
 )
@@ -39,58 +45,139 @@ const (
 	lenH    = 64
 )
 
+// Config domain-separates F4Jumble's hashing and bounds its accepted message
+// length, so that downstream protocols can get their own cascading encoding
+// without forking this package -- the same role BLAKE2b's own
+// personalization parameter plays for BLAKE2b itself.
+//
+// DefaultConfig reproduces the ZIP-316 unified address parameters used by
+// the package-level F4Jumble/F4JumbleInv.
+type Config struct {
+	// HPersonalization and GPersonalization domain-separate the H-round and
+	// G-round hashes of this Config from those of any other Config. BLAKE2b
+	// personalization strings are 16 bytes; the last 3 are reserved by
+	// F4Jumble itself to encode the round number (and, for the G round, the
+	// block index), leaving 13 configurable bytes.
+	HPersonalization [13]byte
+	GPersonalization [13]byte
+
+	// MinLen and MaxLen bound the accepted message length, in bytes.
+	//
+	// checkLen requires 2 <= MinLen <= MaxLen, so that both Feistel halves
+	// are non-empty. It does not require MinLen >= 2*lenH (128): a message
+	// shorter than 2*lenH bytes has a left half of len/2 bytes, shorter
+	// than one hash output. DefaultConfig's MinLen is 48.
+	MinLen int
+	MaxLen int
+}
+
+// DefaultConfig is the Config underlying the package-level F4Jumble and
+// F4JumbleInv.
+var DefaultConfig = &Config{
+	HPersonalization: [13]byte{'U', 'A', '_', 'F', '4', 'J', 'u', 'm', 'b', 'l', 'e', '_', 'H'},
+	GPersonalization: [13]byte{'U', 'A', '_', 'F', '4', 'J', 'u', 'm', 'b', 'l', 'e', '_', 'G'},
+	MinLen:           minLenM,
+	MaxLen:           maxLenM,
+}
+
 func ceilDiv(num int, den int) int {
 	return (num + den - 1) / den
 }
 
-func hPers(i int) []byte {
-	return []byte{85, 65, 95, 70, 52, 74, 117, 109, 98, 108, 101, 95, 72, uint8(i), 0, 0}
+func (c *Config) hPers(i int) [16]byte {
+	var p [16]byte
+	copy(p[:13], c.HPersonalization[:])
+	p[13] = uint8(i)
+	return p
 }
 
-func gPers(i int, j int) []byte {
-	return []byte{85, 65, 95, 70, 52, 74, 117, 109, 98, 108, 101, 95, 71, uint8(i), uint8(j & 0xff), uint8(j >> 8)}
+func (c *Config) gPers(i, j int) [16]byte {
+	var p [16]byte
+	copy(p[:13], c.GPersonalization[:])
+	p[13] = uint8(i)
+	p[14] = uint8(j & 0xff)
+	p[15] = uint8(j >> 8)
+	return p
 }
 
-// XOR returns the exclusive OR of two byte slices
-func xor(x, y []byte) []byte {
-	result := make([]byte, len(x))
-	for i := range x {
-		if i <= len(y) {
-			result[i] = x[i] ^ y[i]
-		}
+// checkLen validates lenM against the Config's own length bounds, and
+// that those bounds are themselves sane: MinLen must allow both Feistel
+// halves to be non-empty. MinLen is not required to be at least 2*lenH;
+// see the MinLen field doc.
+func (c *Config) checkLen(lenM int) error {
+	if c.MinLen < 2 || c.MinLen > c.MaxLen {
+		return errors.New("invalid Config: MinLen must be at least 2 and at most MaxLen")
+	}
+	if lenM < c.MinLen || lenM > c.MaxLen {
+		return errors.New("invalid message length")
 	}
-	return result
+	return nil
 }
 
-func gRound(i int, u []byte, lenR int) ([]byte, error) {
-	inner := func(j int) ([]byte, error) {
-		g, err := blake2b.NewDigest(nil, nil, gPers(i, j), lenH)
-		if err != nil {
-			return nil, err
-		}
-		g.Write(u)
-		return g.Sum(nil), nil
+// scratchPool holds reusable byte slices sized to fit the lenL+lenR working
+// buffer used by JumbleInto/UnjumbleInto, so repeated calls reuse one
+// working buffer instead of each allocating a message-sized intermediate.
+// It does not make JumbleInto/UnjumbleInto allocation-free overall: each
+// G/H round still constructs a fresh blake2/blake2b.Digest per call, because
+// that library's Digest.Reset panics rather than letting us reuse one across
+// the different personalization strings each round needs. That cost is
+// unavoidable without forking blake2b, and still scales with message size
+// (see BenchmarkF4JumbleInto).
+var scratchPool = sync.Pool{
+	New: func() interface{} {
+		return new([]byte)
+	},
+}
+
+func getScratch(n int) *[]byte {
+	bp := scratchPool.Get().(*[]byte)
+	if cap(*bp) < n {
+		*bp = make([]byte, n)
+	} else {
+		*bp = (*bp)[:n]
 	}
+	return bp
+}
 
-	var result []byte
+// gRoundInto XORs the G-round keystream for round i, derived from u, into
+// out in place. len(out) determines lenR.
+func (c *Config) gRoundInto(i int, u []byte, out []byte) error {
+	lenR := len(out)
+	var hashBuf [lenH]byte
+	offset := 0
 	for j := 0; j < ceilDiv(lenR, lenH); j++ {
-		hash, err := inner(j)
+		pers := c.gPers(i, j)
+		g, err := blake2b.NewDigest(nil, nil, pers[:], lenH)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		g.Write(u)
+		sum := g.Sum(hashBuf[:0])
+		chunk := out[offset:min(offset+lenH, lenR)]
+		for k := range chunk {
+			chunk[k] ^= sum[k]
 		}
-		result = append(result, hash...)
+		offset += len(chunk)
 	}
-	return result[:lenR], nil
+	return nil
 }
 
-func hRound(i int, u []byte, lenL int) ([]byte, error) {
-	h, err := blake2b.NewDigest(nil, nil, hPers(i), lenL)
+// hRoundInto XORs the H-round keystream for round i, derived from u, into
+// out in place. len(out) determines lenL.
+func (c *Config) hRoundInto(i int, u []byte, out []byte) error {
+	pers := c.hPers(i)
+	h, err := blake2b.NewDigest(nil, nil, pers[:], len(out))
 	if err != nil {
-		return nil, err
+		return err
 	}
-
 	h.Write(u)
-	return h.Sum(nil), nil
+
+	var hashBuf [lenH]byte
+	sum := h.Sum(hashBuf[:0])
+	for k := range out {
+		out[k] ^= sum[k]
+	}
+	return nil
 }
 
 func min(a, b int) int {
@@ -100,6 +187,106 @@ func min(a, b int) int {
 	return b
 }
 
+// Jumble encodes M using this Config's parameters, and returns the encoded
+// message as []byte. Returns an error if the message is an invalid length.
+func (c *Config) Jumble(M []byte) ([]byte, error) {
+	out := make([]byte, len(M))
+	if err := c.JumbleInto(out, M); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// JumbleInto writes the jumbled encoding of src into dst, without
+// allocating an intermediate result. dst and src must have the same length,
+// and may alias one another (e.g. dst == src, to jumble in place).
+// Returns an error if src is an invalid length or if len(dst) != len(src).
+func (c *Config) JumbleInto(dst, src []byte) error {
+	lenM := len(src)
+	if err := c.checkLen(lenM); err != nil {
+		return err
+	}
+	if len(dst) != lenM {
+		return errors.New("dst and src must have the same length")
+	}
+
+	lenL := min(lenH, lenM/2)
+
+	bufp := getScratch(lenM)
+	defer scratchPool.Put(bufp)
+	scratch := *bufp
+	copy(scratch, src)
+
+	left := scratch[:lenL]  // holds a, then y, then c
+	right := scratch[lenL:] // holds b, then x, then d
+
+	if err := c.gRoundInto(0, left, right); err != nil {
+		return err
+	}
+	if err := c.hRoundInto(0, right, left); err != nil {
+		return err
+	}
+	if err := c.gRoundInto(1, left, right); err != nil {
+		return err
+	}
+	if err := c.hRoundInto(1, right, left); err != nil {
+		return err
+	}
+
+	copy(dst, scratch)
+	return nil
+}
+
+// Unjumble inverts Jumble, returning the original un-jumbled bytes. Returns
+// an error if the message is an invalid length.
+func (c *Config) Unjumble(M []byte) ([]byte, error) {
+	out := make([]byte, len(M))
+	if err := c.UnjumbleInto(out, M); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UnjumbleInto writes the inverse jumbled encoding of src into dst, without
+// allocating an intermediate result. dst and src must have the same length,
+// and may alias one another (e.g. dst == src, to un-jumble in place).
+// Returns an error if src is an invalid length or if len(dst) != len(src).
+func (c *Config) UnjumbleInto(dst, src []byte) error {
+	lenM := len(src)
+	if err := c.checkLen(lenM); err != nil {
+		return err
+	}
+	if len(dst) != lenM {
+		return errors.New("dst and src must have the same length")
+	}
+
+	lenL := min(lenH, lenM/2)
+
+	bufp := getScratch(lenM)
+	defer scratchPool.Put(bufp)
+	scratch := *bufp
+	copy(scratch, src)
+
+	left := scratch[:lenL]  // holds c, then y, then a
+	right := scratch[lenL:] // holds d, then x, then b
+
+	if err := c.hRoundInto(1, right, left); err != nil {
+		return err
+	}
+	if err := c.gRoundInto(1, left, right); err != nil {
+		return err
+	}
+	if err := c.hRoundInto(0, right, left); err != nil {
+		return err
+	}
+	if err := c.gRoundInto(0, left, right); err != nil {
+		return err
+	}
+
+	copy(dst, scratch)
+	return nil
+}
+
 // Encodes the given []byte using F4Jumble, and returns the encoded message as []byte.
 // Returns an error if the message is an invalid length.
 //
@@ -118,43 +305,15 @@ func min(a, b int) int {
 //		// It Worked!
 //	}
 func F4Jumble(M []byte) ([]byte, error) {
-	//println("M:", hex.EncodeToString(M)[:20])
-	lenM := len(M)
-	if lenM < minLenM || lenM > maxLenM {
-		return nil, errors.New("invalid message length")
-	}
-
-	lenL := min(lenH, lenM/2)
-	lenR := lenM - lenL
-
-	a := M[:lenL]
-	b := M[lenL:]
-
-	g0, err := gRound(0, a, lenR)
-	if err != nil {
-		return nil, err
-	}
-	x := xor(b, g0)
-
-	h0, err := hRound(0, x, lenL)
-	if err != nil {
-		return nil, err
-	}
-	y := xor(a, h0)
-
-	g1, err := gRound(1, y, lenR)
-	if err != nil {
-		return nil, err
-	}
-	d := xor(x, g1)
-
-	h1, err := hRound(1, d, lenL)
-	if err != nil {
-		return nil, err
-	}
-	c := xor(y, h1)
+	return DefaultConfig.Jumble(M)
+}
 
-	return append(c, d...), nil
+// F4JumbleInto writes the F4Jumble encoding of src into dst, without
+// allocating an intermediate result. dst and src must have the same length,
+// and may alias one another (e.g. dst == src, to jumble in place).
+// Returns an error if src is an invalid length or if len(dst) != len(src).
+func F4JumbleInto(dst, src []byte) error {
+	return DefaultConfig.JumbleInto(dst, src)
 }
 
 // Inverts the F4Jumble operation, returning the original un-jumbled bytes.
@@ -175,39 +334,13 @@ func F4Jumble(M []byte) ([]byte, error) {
 //		// It Worked!
 //	}
 func F4JumbleInv(M []byte) ([]byte, error) {
-	lenM := len(M)
-	if lenM < minLenM || lenM > maxLenM {
-		return nil, errors.New("invalid message length")
-	}
-	lenL := min(lenH, lenM/2)
-	lenR := lenM - lenL
-
-	c := M[:lenL]
-	d := M[lenL:]
-
-	h1, err := hRound(1, d, lenL)
-	if err != nil {
-		return nil, err
-	}
-	y := xor(c, h1)
-
-	g1, err := gRound(1, y, lenR)
-	if err != nil {
-		return nil, err
-	}
-	x := xor(d, g1)
-
-	h0, err := hRound(0, x, lenL)
-	if err != nil {
-		return nil, err
-	}
-	a := xor(y, h0)
-
-	g0, err := gRound(0, a, lenR)
-	if err != nil {
-		return nil, err
-	}
-	b := xor(x, g0)
+	return DefaultConfig.Unjumble(M)
+}
 
-	return append(a, b...), nil
+// F4JumbleInvInto writes the inverse F4Jumble of src into dst, without
+// allocating an intermediate result. dst and src must have the same length,
+// and may alias one another (e.g. dst == src, to un-jumble in place).
+// Returns an error if src is an invalid length or if len(dst) != len(src).
+func F4JumbleInvInto(dst, src []byte) error {
+	return DefaultConfig.UnjumbleInto(dst, src)
 }
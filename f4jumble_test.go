@@ -0,0 +1,78 @@
+package f4jumble
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestJumbleUnjumbleRoundTrip(t *testing.T) {
+	for _, n := range []int{minLenM, minLenM + 1, 64, 200, 1000} {
+		msg := make([]byte, n)
+		for i := range msg {
+			msg[i] = byte(i * 7)
+		}
+
+		jumbled, err := F4Jumble(msg)
+		if err != nil {
+			t.Fatalf("F4Jumble(len=%d): %v", n, err)
+		}
+		got, err := F4JumbleInv(jumbled)
+		if err != nil {
+			t.Fatalf("F4JumbleInv(len=%d): %v", n, err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Errorf("round trip mismatch at len=%d", n)
+		}
+	}
+}
+
+func TestJumbleIntoAliasing(t *testing.T) {
+	msg := make([]byte, 200)
+	for i := range msg {
+		msg[i] = byte(i * 3)
+	}
+	want, err := F4Jumble(msg)
+	if err != nil {
+		t.Fatalf("F4Jumble: %v", err)
+	}
+
+	inPlace := append([]byte(nil), msg...)
+	if err := F4JumbleInto(inPlace, inPlace); err != nil {
+		t.Fatalf("F4JumbleInto(dst==src): %v", err)
+	}
+	if !bytes.Equal(inPlace, want) {
+		t.Errorf("F4JumbleInto(dst==src) = %x, want %x", inPlace, want)
+	}
+
+	if err := F4JumbleInvInto(inPlace, inPlace); err != nil {
+		t.Fatalf("F4JumbleInvInto(dst==src): %v", err)
+	}
+	if !bytes.Equal(inPlace, msg) {
+		t.Errorf("F4JumbleInvInto(dst==src) = %x, want %x", inPlace, msg)
+	}
+}
+
+func TestCheckLenRejectsOutOfRange(t *testing.T) {
+	if _, err := F4Jumble(make([]byte, minLenM-1)); err == nil {
+		t.Error("F4Jumble accepted a message shorter than MinLen")
+	}
+	if _, err := F4Jumble(make([]byte, maxLenM+1)); err == nil {
+		t.Error("F4Jumble accepted a message longer than MaxLen")
+	}
+}
+
+func BenchmarkF4JumbleInto(b *testing.B) {
+	for _, n := range []int{minLenM, 1000, 100000} {
+		msg := make([]byte, n)
+		dst := make([]byte, n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := F4JumbleInto(dst, msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
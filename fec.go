@@ -0,0 +1,213 @@
+package f4jumble
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+// lenFECPrefix is the size, in bytes, of the length prefix that precedes
+// the jumbled payload inside the Reed-Solomon framing used by
+// F4JumbleWithFEC/F4JumbleInvWithFEC. It records the length of the jumbled
+// payload so Join can discard the padding added to fill out the last shard.
+const lenFECPrefix = 2
+
+// shardAlignment is the number of bytes each FEC shard is padded up to a
+// multiple of. A single character of the downstream text encoding covers
+// at most 2 adjacent bytes within its own 3-byte group (Base64) or 5-byte
+// group (Bech32), and never crosses into a neighbouring group. Aligning
+// every shard boundary to the LCM of those group sizes guarantees that no
+// group -- and therefore no single mistyped character -- straddles two
+// shards, so at most one shard is ever corrupted by one typo.
+const shardAlignment = 15 // lcm(3, 5)
+
+// F4JumbleWithFEC jumbles msg and wraps the result in a systematic
+// Reed-Solomon code split across dataShards data shards and parityShards
+// parity shards, suitable for display via a downstream text encoding (such
+// as Bech32m or Base64) applied directly to the returned bytes, which a
+// human may mistype. The [jumbled] sub-package's Encoding is not such an
+// encoding here: it applies F4Jumble to its input again, so one mistyped
+// character in its output corrupts every shard.
+//
+// The Reed-Solomon code protects the jumbled bytes, not msg itself: F4Jumble
+// is a cascading transform by design (see the package doc), so a single
+// corrupted jumbled byte turns into near-total corruption of the un-jumbled
+// message once F4JumbleInv is applied, which no reasonable error-correcting
+// code could undo. Protecting the jumbled bytes directly instead means a
+// single mistyped character in the text encoding corrupts only the bytes
+// belonging to one Reed-Solomon shard (see shardAlignment), which
+// F4JumbleInvWithFEC can locate and recover before it unjumbles.
+//
+// [jumbled]: https://pkg.go.dev/github.com/MarcoEzekiel/go-f4jumble/jumbled
+func F4JumbleWithFEC(msg []byte, dataShards, parityShards int) ([]byte, error) {
+	jumbled, err := F4Jumble(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(jumbled) > 1<<16-1-lenFECPrefix {
+		return nil, errors.New("message too large to frame for FEC")
+	}
+
+	enc, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, lenFECPrefix+len(jumbled))
+	binary.BigEndian.PutUint16(payload, uint16(len(jumbled)))
+	copy(payload[lenFECPrefix:], jumbled)
+
+	perShard := ceilDiv(len(payload), dataShards)
+	if rem := perShard % shardAlignment; rem != 0 {
+		perShard += shardAlignment - rem
+	}
+
+	totalShards := dataShards + parityShards
+	buf := make([]byte, dataShards*perShard)
+	copy(buf, payload)
+
+	shards := make([][]byte, totalShards)
+	for i := 0; i < dataShards; i++ {
+		shards[i] = buf[i*perShard : (i+1)*perShard]
+	}
+	for i := dataShards; i < totalShards; i++ {
+		shards[i] = make([]byte, perShard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, perShard*totalShards)
+	for i, s := range shards {
+		copy(out[i*perShard:], s)
+	}
+	return out, nil
+}
+
+// F4JumbleInvWithFEC reverses F4JumbleWithFEC. If up to MaxErasures(dataShards,
+// parityShards) of the dataShards+parityShards shards were corrupted -- the
+// expected fallout of that many mistyped characters in the downstream text
+// encoding -- the corrupted shards are located and reconstructed from the
+// rest before the jumbling is undone. Returns an error if more shards than
+// that are corrupted.
+func F4JumbleInvWithFEC(encoded []byte, dataShards, parityShards int) ([]byte, error) {
+	enc, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return nil, err
+	}
+
+	totalShards := dataShards + parityShards
+	if len(encoded) == 0 || len(encoded)%totalShards != 0 {
+		return nil, errors.New("encoded length is not a multiple of the shard count")
+	}
+	perShard := len(encoded) / totalShards
+	if perShard < lenFECPrefix {
+		return nil, errors.New("FEC shard too small to hold the length prefix")
+	}
+
+	shards := make([][]byte, totalShards)
+	for i := range shards {
+		shards[i] = encoded[i*perShard : (i+1)*perShard]
+	}
+
+	if ok, err := enc.Verify(shards); err != nil {
+		return nil, err
+	} else if !ok {
+		shards, err = reconstructCorruptedShards(enc, shards, parityShards)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lenJumbled := int(binary.BigEndian.Uint16(shards[0][:lenFECPrefix]))
+
+	var buf bytes.Buffer
+	if err := enc.Join(&buf, shards, lenFECPrefix+lenJumbled); err != nil {
+		return nil, err
+	}
+	return F4JumbleInv(buf.Bytes()[lenFECPrefix:])
+}
+
+// reconstructCorruptedShards locates and fixes up to
+// MaxErasures(len(shards)-parityShards, parityShards) shards whose content
+// is wrong but whose position is not known in advance.
+//
+// Unlike erasures (whose position the caller already knows, and which a
+// systematic Reed-Solomon code can always recover up to parityShards of),
+// locating an error blind costs two parity shards per error: guessing a
+// wrong set of e erased positions leaves at least one genuinely corrupted
+// shard un-erased, and as long as enough other shards remain to check
+// against, that guess fails Verify. Trying guesses from smallest to
+// largest and stopping at the first that verifies is therefore sound only
+// up to e = parityShards/2; beyond that a wrong guess can no longer be
+// distinguished from the truth, which is why MaxErasures reports
+// parityShards/2 rather than parityShards.
+func reconstructCorruptedShards(enc reedsolomon.Encoder, shards [][]byte, parityShards int) ([][]byte, error) {
+	maxErrors := MaxErasures(len(shards)-parityShards, parityShards)
+	for numErrors := 1; numErrors <= maxErrors; numErrors++ {
+		if fixed := tryAllErasureSets(enc, shards, numErrors); fixed != nil {
+			return fixed, nil
+		}
+	}
+	return nil, errors.New("unable to recover from FEC: too many shards are corrupted")
+}
+
+// tryAllErasureSets tries every way of erasing exactly numErasures of
+// shards, reconstructing and verifying each, and returns the first
+// candidate that verifies correctly, or nil if none do.
+func tryAllErasureSets(enc reedsolomon.Encoder, shards [][]byte, numErasures int) [][]byte {
+	indices := make([]int, numErasures)
+	for i := range indices {
+		indices[i] = i
+	}
+
+	for {
+		candidate := make([][]byte, len(shards))
+		copy(candidate, shards)
+		for _, i := range indices {
+			candidate[i] = nil
+		}
+		if err := enc.Reconstruct(candidate); err == nil {
+			if ok, err := enc.Verify(candidate); err == nil && ok {
+				return candidate
+			}
+		}
+
+		if !nextCombination(indices, len(shards)) {
+			return nil
+		}
+	}
+}
+
+// nextCombination advances indices (a strictly increasing list of indices
+// into a set of size n) to the next combination in lexicographic order,
+// returning false once all combinations have been produced.
+func nextCombination(indices []int, n int) bool {
+	k := len(indices)
+	i := k - 1
+	for ; i >= 0 && indices[i] == n-k+i; i-- {
+	}
+	if i < 0 {
+		return false
+	}
+	indices[i]++
+	for j := i + 1; j < k; j++ {
+		indices[j] = indices[j-1] + 1
+	}
+	return true
+}
+
+// MaxErasures reports how many of the dataShards+parityShards shards
+// produced by F4JumbleWithFEC can be corrupted -- with their position
+// unknown, e.g. because a text character was mistyped -- while still
+// allowing F4JumbleInvWithFEC to locate and recover them.
+//
+// This is parityShards/2, not parityShards: locating a corrupted shard
+// blind costs twice as much redundancy as recovering a shard whose
+// position is already known (an erasure). See reconstructCorruptedShards
+// for why.
+func MaxErasures(dataShards, parityShards int) int {
+	return parityShards / 2
+}
@@ -0,0 +1,104 @@
+package f4jumble
+
+import (
+	"bytes"
+	"testing"
+)
+
+func corruptShard(encoded []byte, totalShards, shardIndex, byteOffset int) []byte {
+	out := append([]byte(nil), encoded...)
+	perShard := len(out) / totalShards
+	out[shardIndex*perShard+byteOffset] ^= 0xff
+	return out
+}
+
+func TestF4JumbleWithFECRoundTrip(t *testing.T) {
+	msg := make([]byte, 50)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+
+	encoded, err := F4JumbleWithFEC(msg, 7, 4)
+	if err != nil {
+		t.Fatalf("F4JumbleWithFEC: %v", err)
+	}
+	got, err := F4JumbleInvWithFEC(encoded, 7, 4)
+	if err != nil {
+		t.Fatalf("F4JumbleInvWithFEC: %v", err)
+	}
+	if !bytes.Equal(got, msg) {
+		t.Errorf("round trip mismatch: got %x, want %x", got, msg)
+	}
+}
+
+func TestF4JumbleInvWithFECRecoversUpToMaxErasures(t *testing.T) {
+	const dataShards, parityShards = 7, 4
+	max := MaxErasures(dataShards, parityShards)
+	if max != 2 {
+		t.Fatalf("MaxErasures(%d, %d) = %d, want 2", dataShards, parityShards, max)
+	}
+
+	msg := make([]byte, 50)
+	for i := range msg {
+		msg[i] = byte(i * 3)
+	}
+	encoded, err := F4JumbleWithFEC(msg, dataShards, parityShards)
+	if err != nil {
+		t.Fatalf("F4JumbleWithFEC: %v", err)
+	}
+	totalShards := dataShards + parityShards
+
+	corrupted := corruptShard(encoded, totalShards, 1, 0)
+	corrupted = corruptShard(corrupted, totalShards, 5, 2)
+
+	got, err := F4JumbleInvWithFEC(corrupted, dataShards, parityShards)
+	if err != nil {
+		t.Fatalf("F4JumbleInvWithFEC with %d corrupted shards: %v", max, err)
+	}
+	if !bytes.Equal(got, msg) {
+		t.Errorf("recovered message mismatch: got %x, want %x", got, msg)
+	}
+}
+
+func TestF4JumbleInvWithFECFailsPastMaxErasures(t *testing.T) {
+	const dataShards, parityShards = 7, 4
+	msg := make([]byte, 50)
+	for i := range msg {
+		msg[i] = byte(i * 5)
+	}
+	encoded, err := F4JumbleWithFEC(msg, dataShards, parityShards)
+	if err != nil {
+		t.Fatalf("F4JumbleWithFEC: %v", err)
+	}
+	totalShards := dataShards + parityShards
+
+	corrupted := corruptShard(encoded, totalShards, 1, 0)
+	corrupted = corruptShard(corrupted, totalShards, 4, 1)
+	corrupted = corruptShard(corrupted, totalShards, 8, 2)
+
+	if _, err := F4JumbleInvWithFEC(corrupted, dataShards, parityShards); err == nil {
+		t.Error("F4JumbleInvWithFEC recovered from more than MaxErasures corrupted shards, want an error")
+	}
+}
+
+func TestF4JumbleInvWithFECSingleParityShardNeverGuesses(t *testing.T) {
+	const dataShards, parityShards = 7, 1
+	if max := MaxErasures(dataShards, parityShards); max != 0 {
+		t.Fatalf("MaxErasures(%d, %d) = %d, want 0", dataShards, parityShards, max)
+	}
+
+	msg := make([]byte, 50)
+	for i := range msg {
+		msg[i] = byte(i * 13)
+	}
+	encoded, err := F4JumbleWithFEC(msg, dataShards, parityShards)
+	if err != nil {
+		t.Fatalf("F4JumbleWithFEC: %v", err)
+	}
+	totalShards := dataShards + parityShards
+
+	corrupted := corruptShard(encoded, totalShards, 1, 0)
+	if _, err := F4JumbleInvWithFEC(corrupted, dataShards, parityShards); err == nil {
+		t.Error("F4JumbleInvWithFEC silently recovered with only 1 parity shard, want an error")
+	}
+}
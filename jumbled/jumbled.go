@@ -0,0 +1,225 @@
+// Package jumbled provides a high-level [Encoding] that fuses F4Jumble with
+// a textual encoding (Bech32m or Base64), following the layered style of
+// [encoding/base32], [encoding/base64] and [encoding/ascii85]: callers who
+// just want "jumble, then encode for human eyes" get that behind one call
+// instead of chaining [f4jumble.F4Jumble] with a separate encoder
+// themselves.
+//
+// [encoding/base32]: https://pkg.go.dev/encoding/base32
+// [encoding/base64]: https://pkg.go.dev/encoding/base64
+// [encoding/ascii85]: https://pkg.go.dev/encoding/ascii85
+package jumbled
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+
+	f4jumble "github.com/MarcoEzekiel/go-f4jumble"
+)
+
+// codec converts an already-jumbled byte slice to and from its textual
+// representation. Encoding implementations plug one of these in to support
+// a given target encoding.
+type codec interface {
+	Encode(jumbled []byte) (string, error)
+	Decode(s string) ([]byte, error)
+	EncodedLen(n int) int
+}
+
+// Encoding bundles F4Jumble with a textual encoding, so that encoding and
+// decoding a message for human verification is a single call.
+type Encoding struct {
+	codec codec
+}
+
+// NewBech32m returns an Encoding that renders jumbled messages as Bech32m
+// strings (BIP-350) with the given human-readable part, as used by ZIP-316
+// unified addresses.
+func NewBech32m(hrp string) *Encoding {
+	return &Encoding{codec: bech32mCodec{hrp: hrp}}
+}
+
+// NewBase64 returns an Encoding that renders jumbled messages using the
+// given base64 encoding.
+func NewBase64(enc *base64.Encoding) *Encoding {
+	return &Encoding{codec: base64Codec{enc: enc}}
+}
+
+// EncodeToString jumbles src and returns its textual encoding.
+func (e *Encoding) EncodeToString(src []byte) (string, error) {
+	jumbled, err := f4jumble.F4Jumble(src)
+	if err != nil {
+		return "", err
+	}
+	return e.codec.Encode(jumbled)
+}
+
+// DecodeString decodes s and reverses the F4Jumble transform, returning the
+// original message.
+func (e *Encoding) DecodeString(s string) ([]byte, error) {
+	jumbled, err := e.codec.Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	return f4jumble.F4JumbleInv(jumbled)
+}
+
+// Encode jumbles src and writes its textual encoding to dst, which must be
+// at least EncodedLen(len(src)) bytes long. It panics if src is an invalid
+// message length for F4Jumble, or if dst is shorter than EncodedLen(len(src)).
+func (e *Encoding) Encode(dst, src []byte) {
+	s, err := e.EncodeToString(src)
+	if err != nil {
+		panic("jumbled: " + err.Error())
+	}
+	if len(dst) < len(s) {
+		panic("jumbled: dst is shorter than EncodedLen(len(src))")
+	}
+	copy(dst, s)
+}
+
+// Decode decodes src and writes the original message to dst, returning the
+// number of bytes written.
+func (e *Encoding) Decode(dst []byte, src string) (int, error) {
+	msg, err := e.DecodeString(src)
+	if err != nil {
+		return 0, err
+	}
+	return copy(dst, msg), nil
+}
+
+// EncodedLen returns the length of the textual encoding of a jumbled
+// message of n bytes.
+func (e *Encoding) EncodedLen(n int) int {
+	return e.codec.EncodedLen(n)
+}
+
+// NewWriter returns a Writer that buffers writes and, on Close, jumbles and
+// encodes the buffered message to w.
+func (e *Encoding) NewWriter(w io.Writer) *Writer {
+	return &Writer{enc: e, w: w}
+}
+
+// NewReader returns a Reader that, on the first Read, consumes r in full,
+// decodes it and reverses the F4Jumble transform, then serves the result.
+func (e *Encoding) NewReader(r io.Reader) *Reader {
+	return &Reader{enc: e, r: r}
+}
+
+// Writer buffers everything written to it and only jumbles and encodes the
+// buffered message once Close is called, since F4Jumble is not a streaming
+// transform: it must see the whole message before it can produce any
+// output.
+type Writer struct {
+	enc *Encoding
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+// Write appends p to the buffered message. It never fails.
+func (wr *Writer) Write(p []byte) (int, error) {
+	return wr.buf.Write(p)
+}
+
+// Close jumbles and encodes the buffered message and writes the result to
+// the underlying writer.
+func (wr *Writer) Close() error {
+	s, err := wr.enc.EncodeToString(wr.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(wr.w, s)
+	return err
+}
+
+// Reader reads and decodes the full encoded message from the underlying
+// reader on the first call to Read, since F4Jumble is not a streaming
+// transform: the whole encoded message must be available before it can be
+// unjumbled.
+type Reader struct {
+	enc *Encoding
+	r   io.Reader
+	buf *bytes.Reader
+}
+
+// Read serves the decoded message, reading and decoding it from the
+// underlying reader first if this is the first call.
+func (rd *Reader) Read(p []byte) (int, error) {
+	if rd.buf == nil {
+		data, err := io.ReadAll(rd.r)
+		if err != nil {
+			return 0, err
+		}
+		msg, err := rd.enc.DecodeString(string(data))
+		if err != nil {
+			return 0, err
+		}
+		rd.buf = bytes.NewReader(msg)
+	}
+	return rd.buf.Read(p)
+}
+
+// base64Codec encodes jumbled messages with a standard encoding/base64
+// codec.
+type base64Codec struct {
+	enc *base64.Encoding
+}
+
+func (c base64Codec) Encode(jumbled []byte) (string, error) {
+	return c.enc.EncodeToString(jumbled), nil
+}
+
+func (c base64Codec) Decode(s string) ([]byte, error) {
+	return c.enc.DecodeString(s)
+}
+
+func (c base64Codec) EncodedLen(n int) int {
+	return c.enc.EncodedLen(n)
+}
+
+// bech32mCodec encodes jumbled messages as Bech32m strings (BIP-350) with a
+// fixed human-readable part.
+type bech32mCodec struct {
+	hrp string
+}
+
+func (c bech32mCodec) Encode(jumbled []byte) (string, error) {
+	data, err := bech32.ConvertBits(jumbled, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return bech32.EncodeM(c.hrp, data)
+}
+
+func (c bech32mCodec) Decode(s string) ([]byte, error) {
+	// ZIP-316 unified addresses can exceed BIP-173's 90-character limit for
+	// multi-receiver types, so we use the uncapped decoder rather than
+	// Decode/DecodeGeneric. DecodeNoLimit doesn't report which checksum
+	// variant matched, so we confirm bech32m (as opposed to plain bech32)
+	// by re-encoding and comparing.
+	hrp, data, err := bech32.DecodeNoLimit(s)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != c.hrp {
+		return nil, fmt.Errorf("jumbled: unexpected human-readable part %q, want %q", hrp, c.hrp)
+	}
+	reencoded, err := bech32.EncodeM(hrp, data)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(reencoded, s) {
+		return nil, fmt.Errorf("jumbled: %q is not a valid bech32m string", s)
+	}
+	return bech32.ConvertBits(data, 5, 8, false)
+}
+
+func (c bech32mCodec) EncodedLen(n int) int {
+	dataChars := (n*8 + 4) / 5
+	return len(c.hrp) + 1 + dataChars + 6
+}
@@ -0,0 +1,105 @@
+package jumbled
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+)
+
+func testMessage(n int) []byte {
+	msg := make([]byte, n)
+	for i := range msg {
+		msg[i] = byte(i * 11)
+	}
+	return msg
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	msg := testMessage(48)
+
+	encodings := map[string]*Encoding{
+		"bech32m": NewBech32m("zs"),
+		"base64":  NewBase64(base64.StdEncoding),
+	}
+	for name, enc := range encodings {
+		t.Run(name, func(t *testing.T) {
+			s, err := enc.EncodeToString(msg)
+			if err != nil {
+				t.Fatalf("EncodeToString: %v", err)
+			}
+			got, err := enc.DecodeString(s)
+			if err != nil {
+				t.Fatalf("DecodeString: %v", err)
+			}
+			if !bytes.Equal(got, msg) {
+				t.Errorf("round trip mismatch: got %x, want %x", got, msg)
+			}
+		})
+	}
+}
+
+func TestEncodeDstTooShortPanics(t *testing.T) {
+	msg := testMessage(48)
+	enc := NewBech32m("zs")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Encode with a too-short dst did not panic")
+		}
+	}()
+	dst := make([]byte, 3)
+	enc.Encode(dst, msg)
+}
+
+func TestEncodeIntoLargeEnoughDst(t *testing.T) {
+	msg := testMessage(48)
+	enc := NewBech32m("zs")
+
+	dst := make([]byte, enc.EncodedLen(len(msg)))
+	enc.Encode(dst, msg)
+
+	roundTrip := make([]byte, len(msg))
+	n, err := enc.Decode(roundTrip, string(dst))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if n != len(msg) || !bytes.Equal(roundTrip[:n], msg) {
+		t.Errorf("Decode = %x, want %x", roundTrip[:n], msg)
+	}
+}
+
+func TestBech32mRejectsWrongHRP(t *testing.T) {
+	msg := testMessage(48)
+	s, err := NewBech32m("zs").EncodeToString(msg)
+	if err != nil {
+		t.Fatalf("EncodeToString: %v", err)
+	}
+	if _, err := NewBech32m("other").DecodeString(s); err == nil {
+		t.Error("DecodeString accepted a string with the wrong human-readable part")
+	}
+}
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+	msg := testMessage(48)
+	enc := NewBase64(base64.StdEncoding)
+
+	var buf bytes.Buffer
+	w := enc.NewWriter(&buf)
+	if _, err := w.Write(msg); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r := enc.NewReader(strings.NewReader(buf.String()))
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, msg) {
+		t.Errorf("Writer/Reader round trip mismatch: got %x, want %x", got, msg)
+	}
+}